@@ -0,0 +1,40 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Config holds the repo and auth settings shared by every list*/Forge/
+// mode call in this tool.
+type Config struct {
+	// Owner and Repo name the target repository. For the gitlab provider
+	// they're joined as "Owner/Repo" to form the GitLab project path.
+	Owner string
+	Repo  string
+
+	// Token authenticates a single-token run. Tokens, if non-empty, is
+	// preferred instead; httpclient.Transport sends each request with the
+	// pool's token that has the most remaining quota.
+	Token  string
+	Tokens []string
+
+	// StartDate/EndDate bound the --mode handlers and the time-filtered
+	// list* queries; both are parsed with parseDate.
+	StartDate string
+	EndDate   string
+
+	// Provider selects the Forge implementation: "github" (default),
+	// "gitlab", "gitea" or "gogs". BaseURL points it at a self-hosted
+	// instance; it's required for gitea and gogs.
+	Provider string
+	BaseURL  string
+}
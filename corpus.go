@@ -0,0 +1,404 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/juju/errors"
+)
+
+// corpusSnapshot is the on-disk representation of everything we know about
+// a single owner/repo. It is refreshed incrementally by Corpus.Update and
+// read back by the query methods below.
+type corpusSnapshot struct {
+	// ETags holds the last-seen ETag for each conditionally-fetched
+	// resource ("stargazers", "forkers"), keyed so Update can send it back
+	// as If-None-Match and skip the refetch entirely when GitHub answers
+	// 304 Not Modified.
+	ETags map[string]string `json:"etags"`
+
+	// Profiles caches full stargazer/forker profiles by ID, so a user
+	// already seen on an earlier run doesn't cost another Users.GetByID
+	// call. Watchers, issue authors and commit authors are fetched through
+	// listWatchers/listIssues/listCommits, which do not consult it.
+	Profiles map[int]*github.User `json:"profiles"`
+
+	Stargazers    []*github.User `json:"stargazers"`
+	StargazedAt   []time.Time    `json:"stargazed_at"`
+	Forkers       []*github.User `json:"forkers"`
+	ForkedAt      []time.Time    `json:"forked_at"`
+	Watchers      []*github.User `json:"watchers"`
+	IssueAuthors  []*github.User `json:"issue_authors"`
+	CommitAuthors []*github.User `json:"commit_authors"`
+
+	// UpdatedAt is when this snapshot was last refreshed from upstream.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Corpus is a persistent local mirror of a repo's commits, issues, stars,
+// forks and watchers, modeled after golang.org/x/build/maintner's local
+// corpus. The query methods below run entirely offline once Update has
+// been called at least once; see Update's doc comment for how much of
+// each run is actually incremental today.
+type Corpus struct {
+	mu   sync.RWMutex
+	dir  string
+	data map[string]*corpusSnapshot // keyed by "owner/repo"
+}
+
+// NewCorpus opens the on-disk corpus rooted at dir, creating it if it
+// doesn't exist yet.
+func NewCorpus(dir string) (*Corpus, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return &Corpus{
+		dir:  dir,
+		data: make(map[string]*corpusSnapshot),
+	}, nil
+}
+
+func (c *Corpus) snapshotPath(owner, repo string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s-%s.json", owner, repo))
+}
+
+func (c *Corpus) load(owner, repo string) (*corpusSnapshot, error) {
+	key := owner + "/" + repo
+
+	c.mu.RLock()
+	snap, ok := c.data[key]
+	c.mu.RUnlock()
+	if ok {
+		return snap, nil
+	}
+
+	snap = &corpusSnapshot{}
+	f, err := os.Open(c.snapshotPath(owner, repo))
+	if os.IsNotExist(err) {
+		return snap, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(snap); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return snap, nil
+}
+
+func (c *Corpus) save(owner, repo string, snap *corpusSnapshot) error {
+	key := owner + "/" + repo
+
+	f, err := os.Create(c.snapshotPath(owner, repo))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer f.Close()
+
+	if err := json.NewEncoder(f).Encode(snap); err != nil {
+		return errors.Trace(err)
+	}
+
+	c.mu.Lock()
+	c.data[key] = snap
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Update refreshes the corpus for cfg.Owner/cfg.Repo. Stargazers and
+// forkers are refreshed conditionally: Update sends the ETag recorded
+// from the previous run as If-None-Match, and when GitHub answers 304 Not
+// Modified it makes no further requests for that resource at all. When
+// the resource did change (or refresh is true, or there's no prior
+// snapshot), it re-pages the resource but only calls Users.GetByID for
+// users not already in the profile cache.
+//
+// TODO: watchers/issues/commits are still walked in full on every call —
+// GitHub's REST list endpoints for those don't expose a cheap way to
+// detect "nothing changed" the way a single ETag'd stargazers/forks page
+// does, so making them incremental needs the events API instead. They
+// also bypass the profile cache: listWatchers and listIssues call
+// Users.GetByID for each user they return, on every call.
+func (c *Corpus) Update(ctx context.Context, client *github.Client, cfg *Config, refresh bool) error {
+	snap, err := c.load(cfg.Owner, cfg.Repo)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if refresh {
+		*snap = corpusSnapshot{}
+	}
+	if snap.ETags == nil {
+		snap.ETags = make(map[string]string)
+	}
+	if snap.Profiles == nil {
+		snap.Profiles = make(map[int]*github.User)
+	}
+
+	if err := c.updateStargazers(ctx, client, cfg, snap); err != nil {
+		return errors.Trace(err)
+	}
+
+	if err := c.updateForkers(ctx, client, cfg, snap); err != nil {
+		return errors.Trace(err)
+	}
+
+	watchers, _, err := listWatchers(ctx, client, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	snap.Watchers = watchers
+
+	issueUsers, err := listIssues(ctx, client, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	snap.IssueAuthors = issueUsers
+
+	commitUsers, err := listCommits(ctx, client, cfg, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	snap.CommitAuthors = commitUsers
+
+	snap.UpdatedAt = time.Now()
+
+	return errors.Trace(c.save(cfg.Owner, cfg.Repo, snap))
+}
+
+// profile returns the cached full user for id, fetching and caching it on
+// first sight. Stargazer/fork API responses only carry a partial user
+// (id + login), so without this cache every run would pay a GetByID call
+// per user even when nothing about them changed.
+func (c *Corpus) profile(ctx context.Context, client *github.Client, snap *corpusSnapshot, id int) (*github.User, error) {
+	if user, ok := snap.Profiles[id]; ok {
+		return user, nil
+	}
+
+	user, _, err := client.Users.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	snap.Profiles[id] = user
+
+	return user, nil
+}
+
+// updateStargazers conditionally refreshes snap.Stargazers/StargazedAt:
+// it checks the first stargazers page against the stored ETag and, on a
+// 304, leaves the snapshot untouched.
+func (c *Corpus) updateStargazers(ctx context.Context, client *github.Client, cfg *Config, snap *corpusSnapshot) error {
+	firstPage, etag, notModified, err := conditionalFirstPage(ctx, client, cfg, "stargazers", snap.ETags["stargazers"])
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if notModified {
+		return nil
+	}
+	snap.ETags["stargazers"] = etag
+
+	opt := &github.ListOptions{PerPage: 100, Page: 2}
+	var all []*github.Stargazer
+	if err := json.Unmarshal(firstPage, &all); err != nil {
+		return errors.Trace(err)
+	}
+	for {
+		var (
+			stargazers []*github.Stargazer
+			resp       *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			stargazers, resp, err = client.Activity.ListStargazers(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
+			return errors.Trace(err)
+		}
+		all = append(all, stargazers...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	var (
+		users []*github.User
+		times []time.Time
+	)
+	for _, sg := range all {
+		if sg.User == nil || sg.User.ID == nil {
+			continue
+		}
+
+		user, err := c.profile(ctx, client, snap, *sg.User.ID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		users = append(users, user)
+		times = append(times, sg.StarredAt.Time)
+	}
+	snap.Stargazers = users
+	snap.StargazedAt = times
+
+	return nil
+}
+
+// updateForkers is the fork counterpart to updateStargazers.
+func (c *Corpus) updateForkers(ctx context.Context, client *github.Client, cfg *Config, snap *corpusSnapshot) error {
+	firstPage, etag, notModified, err := conditionalFirstPage(ctx, client, cfg, "forks", snap.ETags["forkers"])
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if notModified {
+		return nil
+	}
+	snap.ETags["forkers"] = etag
+
+	opt := &github.RepositoryListForksOptions{ListOptions: github.ListOptions{PerPage: 100, Page: 2}}
+	var all []*github.Repository
+	if err := json.Unmarshal(firstPage, &all); err != nil {
+		return errors.Trace(err)
+	}
+	for {
+		var (
+			repos []*github.Repository
+			resp  *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			repos, resp, err = client.Repositories.ListForks(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
+			return errors.Trace(err)
+		}
+		all = append(all, repos...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	var (
+		users []*github.User
+		times []time.Time
+	)
+	for _, repo := range all {
+		if repo.Owner == nil || repo.Owner.ID == nil {
+			continue
+		}
+
+		user, err := c.profile(ctx, client, snap, *repo.Owner.ID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		users = append(users, user)
+		times = append(times, repo.CreatedAt.Time)
+	}
+	snap.Forkers = users
+	snap.ForkedAt = times
+
+	return nil
+}
+
+// conditionalFirstPage fetches page 1 of resource ("stargazers" or
+// "forks") for cfg.Owner/cfg.Repo with an If-None-Match header built from
+// etag. It returns notModified=true (with no body) on a 304, so Update
+// can skip the rest of the resource entirely when nothing changed.
+func conditionalFirstPage(ctx context.Context, client *github.Client, cfg *Config, resource, etag string) (body []byte, newETag string, notModified bool, err error) {
+	path := fmt.Sprintf("repos/%s/%s/%s?per_page=100&page=1", cfg.Owner, cfg.Repo, resource)
+
+	req, err := client.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, "", false, errors.Trace(err)
+	}
+	if resource == "stargazers" {
+		// Needed to get starred_at timestamps back from this endpoint.
+		req.Header.Set("Accept", "application/vnd.github.star+json")
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	var raw json.RawMessage
+	resp, err := client.Do(ctx, req, &raw)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return nil, etag, true, nil
+	}
+	if err != nil {
+		return nil, "", false, errors.Trace(err)
+	}
+
+	return raw, resp.Header.Get("ETag"), false, nil
+}
+
+// Stargazers returns everyone who starred owner/repo between start and end,
+// read entirely from the local corpus. Update must have been called first.
+func (c *Corpus) Stargazers(owner, repo string, start, end time.Time) ([]*github.User, []time.Time, error) {
+	snap, err := c.load(owner, repo)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	var (
+		users []*github.User
+		times []time.Time
+	)
+	for i, t := range snap.StargazedAt {
+		if !checkTime(start, end, t) {
+			continue
+		}
+		users = append(users, snap.Stargazers[i])
+		times = append(times, t)
+	}
+
+	return users, times, nil
+}
+
+// Forkers returns everyone who forked owner/repo between start and end,
+// read entirely from the local corpus.
+func (c *Corpus) Forkers(owner, repo string, start, end time.Time) ([]*github.User, []time.Time, error) {
+	snap, err := c.load(owner, repo)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	var (
+		users []*github.User
+		times []time.Time
+	)
+	for i, t := range snap.ForkedAt {
+		if !checkTime(start, end, t) {
+			continue
+		}
+		users = append(users, snap.Forkers[i])
+		times = append(times, t)
+	}
+
+	return users, times, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/google/go-github/github"
+	"github.com/juju/errors"
+)
+
+// giteaForge implements Forge against a Gitea or Forgejo instance at
+// Config.BaseURL, which is required since neither service has a single
+// public hostname the way github.com does.
+type giteaForge struct {
+	client *gitea.Client
+	owner  string
+	repo   string
+}
+
+func newGiteaForge(cfg *Config) (Forge, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("gitea provider requires Config.BaseURL")
+	}
+
+	client, err := gitea.NewClient(cfg.BaseURL, gitea.SetToken(cfg.Token))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return &giteaForge{client: client, owner: cfg.Owner, repo: cfg.Repo}, nil
+}
+
+func (f *giteaForge) ListStargazers(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	var (
+		users []*github.User
+		times []time.Time
+	)
+	for page := 1; ; page++ {
+		stargazers, _, err := f.client.ListRepoStargazers(f.owner, f.repo, gitea.ListStargazersOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 100},
+		})
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if len(stargazers) == 0 {
+			break
+		}
+
+		for _, u := range stargazers {
+			user := toGitHubUser(int(u.ID), u.UserName, u.FullName, u.Email, u.Location, "", "", u.AvatarURL, 0, 0, u.Followers)
+
+			if sink != nil {
+				if err := sink.WriteUser(userRecord{User: user}); err != nil {
+					return nil, nil, errors.Trace(err)
+				}
+				continue
+			}
+			users = append(users, user)
+			times = append(times, time.Time{})
+		}
+	}
+
+	return users, times, nil
+}
+
+func (f *giteaForge) ListForks(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	forks, _, err := f.client.ListForks(f.owner, f.repo, gitea.ListForksOptions{})
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	var (
+		users []*github.User
+		times []time.Time
+	)
+	for _, fork := range forks {
+		owner := fork.Owner
+		user := toGitHubUser(int(owner.ID), owner.UserName, owner.FullName, owner.Email, owner.Location, "", "", owner.AvatarURL, 0, 0, 0)
+
+		if sink != nil {
+			if err := sink.WriteUser(userRecord{User: user, Time: fork.Created}); err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			continue
+		}
+		users = append(users, user)
+		times = append(times, fork.Created)
+	}
+
+	return users, times, nil
+}
+
+func (f *giteaForge) ListWatchers(ctx context.Context, cfg *Config) ([]*github.User, []time.Time, error) {
+	var users []*github.User
+	for page := 1; ; page++ {
+		watchers, _, err := f.client.ListRepoSubscribers(f.owner, f.repo, gitea.ListWatcherOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 100},
+		})
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		if len(watchers) == 0 {
+			break
+		}
+
+		for _, u := range watchers {
+			users = append(users, toGitHubUser(int(u.ID), u.UserName, u.FullName, u.Email, u.Location, "", "", u.AvatarURL, 0, 0, u.Followers))
+		}
+	}
+
+	return users, nil, nil
+}
+
+func (f *giteaForge) ListIssues(ctx context.Context, cfg *Config) ([]*github.User, error) {
+	var users []*github.User
+	seen := make(map[int64]struct{})
+	for page := 1; ; page++ {
+		issues, _, err := f.client.ListRepoIssues(f.owner, f.repo, gitea.ListIssueOption{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 100},
+		})
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(issues) == 0 {
+			break
+		}
+
+		for _, issue := range issues {
+			if issue.Poster == nil {
+				continue
+			}
+			if _, ok := seen[issue.Poster.ID]; ok {
+				continue
+			}
+			seen[issue.Poster.ID] = struct{}{}
+			users = append(users, toGitHubUser(int(issue.Poster.ID), issue.Poster.UserName, issue.Poster.FullName, issue.Poster.Email, "", "", "", "", 0, 0, 0))
+		}
+	}
+
+	return users, nil
+}
+
+func (f *giteaForge) ListCommits(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, error) {
+	return nil, errors.NotImplementedf("Gitea commit author listing")
+}
+
+func (f *giteaForge) ListCommitRecords(ctx context.Context, cfg *Config) ([]commitRecord, error) {
+	return nil, errors.NotImplementedf("Gitea per-commit records")
+}
+
+func (f *giteaForge) ListIssueRecords(ctx context.Context, cfg *Config) ([]issueRecord, error) {
+	return nil, errors.NotImplementedf("Gitea per-issue records")
+}
+
+func (f *giteaForge) GetUserByID(ctx context.Context, id int) (*github.User, error) {
+	return nil, errors.NotImplementedf("Gitea does not expose a lookup-by-numeric-ID endpoint")
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/juju/errors"
+	gitlab "github.com/xanzy/go-gitlab"
+)
+
+// gitlabForge implements Forge against a GitLab or self-hosted GitLab CE/EE
+// instance. Config.Owner/Config.Repo are joined as "owner/repo" to form the
+// project path GitLab expects.
+type gitlabForge struct {
+	client  *gitlab.Client
+	project string
+}
+
+func newGitLabForge(cfg *Config) (Forge, error) {
+	var (
+		client *gitlab.Client
+		err    error
+	)
+
+	if cfg.BaseURL != "" {
+		client, err = gitlab.NewClient(cfg.Token, gitlab.WithBaseURL(cfg.BaseURL))
+	} else {
+		client, err = gitlab.NewClient(cfg.Token)
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return &gitlabForge{
+		client:  client,
+		project: cfg.Owner + "/" + cfg.Repo,
+	}, nil
+}
+
+func (f *gitlabForge) ListStargazers(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	// GitLab's API exposes a starrers count but not a per-star timestamped
+	// list the way GitHub does; there's nothing to page here yet.
+	return nil, nil, errors.NotImplementedf("GitLab stargazer listing")
+}
+
+func (f *gitlabForge) ListForks(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	opt := &gitlab.ListProjectsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+
+	var (
+		users []*github.User
+		times []time.Time
+	)
+	for {
+		forks, resp, err := f.client.Projects.ListForksOfProject(f.project, &gitlab.ListForksOfProjectOptions{ListOptions: opt.ListOptions})
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+
+		for _, fork := range forks {
+			user := toGitHubUser(fork.Owner.ID, fork.Owner.Username, fork.Owner.Name, "", "", "", "", fork.Owner.WebURL, 0, 0, 0)
+			createdAt := fork.CreatedAt
+			if createdAt == nil {
+				createdAt = &time.Time{}
+			}
+
+			if sink != nil {
+				if err := sink.WriteUser(userRecord{User: user, Time: *createdAt}); err != nil {
+					return nil, nil, errors.Trace(err)
+				}
+				continue
+			}
+
+			users = append(users, user)
+			times = append(times, *createdAt)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return users, times, nil
+}
+
+func (f *gitlabForge) ListWatchers(ctx context.Context, cfg *Config) ([]*github.User, []time.Time, error) {
+	return nil, nil, errors.NotImplementedf("GitLab watcher listing")
+}
+
+func (f *gitlabForge) ListIssues(ctx context.Context, cfg *Config) ([]*github.User, error) {
+	opt := &gitlab.ListProjectIssuesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+
+	var users []*github.User
+	seen := make(map[int]struct{})
+	for {
+		issues, resp, err := f.client.Issues.ListProjectIssues(f.project, opt)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		for _, issue := range issues {
+			if issue.Author == nil {
+				continue
+			}
+			if _, ok := seen[issue.Author.ID]; ok {
+				continue
+			}
+			seen[issue.Author.ID] = struct{}{}
+			users = append(users, toGitHubUser(issue.Author.ID, issue.Author.Username, issue.Author.Name, "", "", "", "", "", 0, 0, 0))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return users, nil
+}
+
+func (f *gitlabForge) ListCommits(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, error) {
+	opt := &gitlab.ListCommitsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+
+	var users []*github.User
+	seen := make(map[string]struct{})
+	for {
+		commits, resp, err := f.client.Commits.ListCommits(f.project, opt)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		for _, commit := range commits {
+			if _, ok := seen[commit.AuthorEmail]; ok {
+				continue
+			}
+			seen[commit.AuthorEmail] = struct{}{}
+			user := toGitHubUser(0, "", commit.AuthorName, commit.AuthorEmail, "", "", "", "", 0, 0, 0)
+
+			if sink != nil {
+				if err := sink.WriteUser(userRecord{User: user}); err != nil {
+					return nil, errors.Trace(err)
+				}
+				continue
+			}
+			users = append(users, user)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	if sink != nil {
+		return nil, nil
+	}
+
+	return users, nil
+}
+
+func (f *gitlabForge) ListCommitRecords(ctx context.Context, cfg *Config) ([]commitRecord, error) {
+	return nil, errors.NotImplementedf("GitLab per-commit records")
+}
+
+func (f *gitlabForge) ListIssueRecords(ctx context.Context, cfg *Config) ([]issueRecord, error) {
+	return nil, errors.NotImplementedf("GitLab per-issue records")
+}
+
+func (f *gitlabForge) GetUserByID(ctx context.Context, id int) (*github.User, error) {
+	user, _, err := f.client.Users.GetUser(id, gitlab.GetUsersOptions{})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return toGitHubUser(user.ID, user.Username, user.Name, user.PublicEmail, user.Location, user.Organization, user.Bio, user.WebsiteURL, 0, 0, 0), nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogs/go-gogs-client"
+	"github.com/google/go-github/github"
+	"github.com/juju/errors"
+)
+
+// gogsForge implements Forge against a Gogs instance at Config.BaseURL.
+// Gogs' API is a strict subset of Gitea's (Gitea forked from it), so
+// several methods here have no upstream endpoint to call at all.
+type gogsForge struct {
+	client *gogs.Client
+	owner  string
+	repo   string
+}
+
+func newGogsForge(cfg *Config) (Forge, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("gogs provider requires Config.BaseURL")
+	}
+
+	return &gogsForge{
+		client: gogs.NewClient(cfg.BaseURL, cfg.Token),
+		owner:  cfg.Owner,
+		repo:   cfg.Repo,
+	}, nil
+}
+
+func (f *gogsForge) ListStargazers(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	return nil, nil, errors.NotImplementedf("Gogs has no stargazer API")
+}
+
+func (f *gogsForge) ListForks(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	forks, err := f.client.ListForks(f.owner, f.repo)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	var users []*github.User
+	for _, fork := range forks {
+		owner := fork.Owner
+		user := toGitHubUser(int(owner.ID), owner.UserName, owner.FullName, owner.Email, "", "", "", owner.AvatarUrl, 0, 0, 0)
+
+		if sink != nil {
+			if err := sink.WriteUser(userRecord{User: user}); err != nil {
+				return nil, nil, errors.Trace(err)
+			}
+			continue
+		}
+		users = append(users, user)
+	}
+
+	return users, nil, nil
+}
+
+func (f *gogsForge) ListWatchers(ctx context.Context, cfg *Config) ([]*github.User, []time.Time, error) {
+	watchers, err := f.client.ListWatchers(f.owner, f.repo)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	var users []*github.User
+	for _, u := range watchers {
+		users = append(users, toGitHubUser(int(u.ID), u.UserName, u.FullName, u.Email, "", "", "", u.AvatarUrl, 0, 0, 0))
+	}
+
+	return users, nil, nil
+}
+
+func (f *gogsForge) ListIssues(ctx context.Context, cfg *Config) ([]*github.User, error) {
+	issues, err := f.client.ListRepoIssues(f.owner, f.repo, gogs.ListIssueOption{})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var users []*github.User
+	seen := make(map[int64]struct{})
+	for _, issue := range issues {
+		if issue.Poster == nil {
+			continue
+		}
+		if _, ok := seen[issue.Poster.ID]; ok {
+			continue
+		}
+		seen[issue.Poster.ID] = struct{}{}
+		users = append(users, toGitHubUser(int(issue.Poster.ID), issue.Poster.UserName, issue.Poster.FullName, issue.Poster.Email, "", "", "", "", 0, 0, 0))
+	}
+
+	return users, nil
+}
+
+func (f *gogsForge) ListCommits(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, error) {
+	return nil, errors.NotImplementedf("Gogs commit author listing")
+}
+
+func (f *gogsForge) ListCommitRecords(ctx context.Context, cfg *Config) ([]commitRecord, error) {
+	return nil, errors.NotImplementedf("Gogs per-commit records")
+}
+
+func (f *gogsForge) ListIssueRecords(ctx context.Context, cfg *Config) ([]issueRecord, error) {
+	return nil, errors.NotImplementedf("Gogs per-issue records")
+}
+
+func (f *gogsForge) GetUserByID(ctx context.Context, id int) (*github.User, error) {
+	return nil, errors.NotImplementedf("Gogs does not expose a lookup-by-numeric-ID endpoint")
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestToGitHubUser(t *testing.T) {
+	user := toGitHubUser(42, "octocat", "The Octocat", "octo@example.com", "SF", "GitHub", "bio", "https://example.com", 1, 2, 3)
+
+	if got := user.GetID(); got != 42 {
+		t.Fatalf("ID = %d, want 42", got)
+	}
+	if got := user.GetLogin(); got != "octocat" {
+		t.Fatalf("Login = %q, want octocat", got)
+	}
+	if got := user.GetPublicRepos(); got != 1 {
+		t.Fatalf("PublicRepos = %d, want 1", got)
+	}
+	if got := user.GetFollowing(); got != 2 {
+		t.Fatalf("Following = %d, want 2", got)
+	}
+	if got := user.GetFollowers(); got != 3 {
+		t.Fatalf("Followers = %d, want 3", got)
+	}
+}
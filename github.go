@@ -17,6 +17,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"sort"
 	"strconv"
@@ -27,18 +28,47 @@ import (
 	"github.com/google/go-github/github"
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/zhexuany/community/httpclient"
 	"golang.org/x/oauth2"
 )
 
+// maxPageRetries bounds how many times a single page is retried after a
+// transient error before the list* functions give up and return it.
+const maxPageRetries = 3
+
 func newClient(ctx context.Context, token string) *github.Client {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
+	return newClientPool(ctx, []string{token})
+}
 
-	tc := oauth2.NewClient(ctx, ts)
+// newClientPool returns a client whose transport sends each request with
+// the token that has the most remaining quota and proactively backs off on
+// rate limits, so a long pagination run doesn't die mid-way through a
+// large repo. Auth is handled by the transport itself rather than
+// golang.org/x/oauth2, since the token to use varies request-to-request;
+// newClient is the single-token case of this.
+func newClientPool(ctx context.Context, tokens []string) *github.Client {
+	tc := &http.Client{Transport: httpclient.NewTransport(tokens, nil)}
 
-	client := github.NewClient(tc)
-	return client
+	return github.NewClient(tc)
+}
+
+// retryPage runs fetch, retrying on error with backoff rather than
+// aborting the whole pagination loop, since a transient network blip
+// shouldn't throw away everything collected on earlier pages.
+func retryPage(fetch func() error) error {
+	var err error
+	for attempt := 0; attempt <= maxPageRetries; attempt++ {
+		if err = fetch(); err == nil {
+			return nil
+		}
+
+		if attempt < maxPageRetries {
+			log.Warnf("page fetch failed, retrying (%d/%d): %v", attempt+1, maxPageRetries, err)
+			time.Sleep(time.Duration(attempt+1) * time.Second)
+		}
+	}
+
+	return errors.Trace(err)
 }
 
 func listPublicRepos(ctx context.Context, client *github.Client, org string) ([]*github.Repository, error) {
@@ -70,7 +100,10 @@ func (s UserSlice) Len() int           { return len(s) }
 func (s UserSlice) Less(i, j int) bool { return *s[i].Login < *s[j].Login }
 func (s UserSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
-func listCommits(ctx context.Context, client *github.Client, cfg *Config) ([]*github.User, error) {
+// listCommits returns the distinct authors of every commit in cfg.Owner/
+// cfg.Repo. When sink is non-nil, each newly-seen author is streamed to it
+// as pages arrive instead of being accumulated in memory.
+func listCommits(ctx context.Context, client *github.Client, cfg *Config, sink Sink) ([]*github.User, error) {
 	opt := &github.CommitsListOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
@@ -79,8 +112,14 @@ func listCommits(ctx context.Context, client *github.Client, cfg *Config) ([]*gi
 		users = make(map[*int]*github.User)
 	)
 	for {
-		commits, resp, err := client.Repositories.ListCommits(ctx, cfg.Owner, cfg.Repo, opt)
-		if err != nil {
+		var (
+			commits []*github.RepositoryCommit
+			resp    *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			commits, resp, err = client.Repositories.ListCommits(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
 			return nil, errors.Trace(err)
 		}
 
@@ -89,6 +128,12 @@ func listCommits(ctx context.Context, client *github.Client, cfg *Config) ([]*gi
 			_, ok := users[user.ID]
 			if !ok {
 				users[user.ID] = &user
+
+				if sink != nil {
+					if err := sink.WriteUser(userRecord{User: &user}); err != nil {
+						return nil, errors.Trace(err)
+					}
+				}
 			}
 		}
 
@@ -99,6 +144,10 @@ func listCommits(ctx context.Context, client *github.Client, cfg *Config) ([]*gi
 		opt.Page = resp.NextPage
 	}
 
+	if sink != nil {
+		return nil, nil
+	}
+
 	var (
 		gUser []*github.User
 	)
@@ -110,7 +159,10 @@ func listCommits(ctx context.Context, client *github.Client, cfg *Config) ([]*gi
 	return gUser, nil
 }
 
-func listForkers(ctx context.Context, client *github.Client, cfg *Config) ([]*github.User, []time.Time, error) {
+// listForkers returns who forked cfg.Owner/cfg.Repo and when. When sink is
+// non-nil, records are streamed to it as pages arrive instead of being
+// accumulated into the returned slices.
+func listForkers(ctx context.Context, client *github.Client, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
 	useTimeFilter := len(cfg.StartDate) > 0 && len(cfg.EndDate) > 0
 
 	var (
@@ -139,8 +191,14 @@ func listForkers(ctx context.Context, client *github.Client, cfg *Config) ([]*gi
 		times []time.Time
 	)
 	for {
-		repos, resp, err := client.Repositories.ListForks(ctx, cfg.Owner, cfg.Repo, opt)
-		if err != nil {
+		var (
+			repos []*github.Repository
+			resp  *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			repos, resp, err = client.Repositories.ListForks(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
 			return nil, nil, errors.Trace(err)
 		}
 
@@ -156,6 +214,13 @@ func listForkers(ctx context.Context, client *github.Client, cfg *Config) ([]*gi
 				return nil, nil, errors.Trace(err)
 			}
 
+			if sink != nil {
+				if err := sink.WriteUser(userRecord{User: user, Time: repo.CreatedAt.Time}); err != nil {
+					return nil, nil, errors.Trace(err)
+				}
+				continue
+			}
+
 			users = append(users, user)
 			times = append(times, repo.CreatedAt.Time)
 		}
@@ -178,8 +243,14 @@ func listWatchers(ctx context.Context, client *github.Client, cfg *Config) ([]*g
 		times    []time.Time
 	)
 	for {
-		users, resp, err := client.Activity.ListWatchers(ctx, cfg.Owner, cfg.Repo, opt)
-		if err != nil {
+		var (
+			users []*github.User
+			resp  *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			users, resp, err = client.Activity.ListWatchers(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
 			return nil, nil, errors.Trace(err)
 		}
 
@@ -211,8 +282,14 @@ func listIssues(ctx context.Context, client *github.Client, cfg *Config) ([]*git
 	var users []*github.User
 	userCache := make(map[int]struct{})
 	for {
-		issues, resp, err := client.Issues.ListByRepo(ctx, cfg.Owner, cfg.Repo, opt)
-		if err != nil {
+		var (
+			issues []*github.Issue
+			resp   *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			issues, resp, err = client.Issues.ListByRepo(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
 			return nil, errors.Trace(err)
 		}
 
@@ -241,7 +318,132 @@ func listIssues(ctx context.Context, client *github.Client, cfg *Config) ([]*git
 	return users, nil
 }
 
-func listStargazers(ctx context.Context, client *github.Client, cfg *Config, onlyID bool) ([]*github.User, []time.Time, error) {
+// commitRecord is one commit's author plus when they authored it. Unlike
+// listCommits, which dedupes down to one entry per author, this keeps
+// every commit so callers (--mode handlers) can bucket by week or rank by
+// count.
+type commitRecord struct {
+	Author *github.User
+	At     time.Time
+}
+
+// listCommitRecords returns every commit's author and commit time for
+// cfg.Owner/cfg.Repo, unlike listCommits which dedupes to one entry per
+// author and drops the timestamp.
+func listCommitRecords(ctx context.Context, client *github.Client, cfg *Config) ([]commitRecord, error) {
+	opt := &github.CommitsListOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var records []commitRecord
+	for {
+		var (
+			commits []*github.RepositoryCommit
+			resp    *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			commits, resp, err = client.Repositories.ListCommits(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		for _, commit := range commits {
+			if commit.Author == nil || commit.Commit == nil || commit.Commit.Author == nil {
+				continue
+			}
+
+			records = append(records, commitRecord{
+				Author: commit.Author,
+				At:     commit.Commit.Author.Date.Time,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return records, nil
+}
+
+// issueRecord is one issue's author plus its open/close timestamps, used
+// by --mode handlers that need time-to-close or weekly issue volume
+// rather than just the distinct set of authors listIssues returns.
+type issueRecord struct {
+	Author    *github.User
+	CreatedAt time.Time
+	ClosedAt  *time.Time
+}
+
+// listIssueRecords returns every issue (open and closed) for cfg.Owner/
+// cfg.Repo with its author and timestamps. Author profiles are cached by
+// ID within the call so repeat authors don't each cost a GetByID round
+// trip.
+func listIssueRecords(ctx context.Context, client *github.Client, cfg *Config) ([]issueRecord, error) {
+	opt := &github.IssueListByRepoOptions{
+		State:       "all",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var records []issueRecord
+	userCache := make(map[int]*github.User)
+	for {
+		var (
+			issues []*github.Issue
+			resp   *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			issues, resp, err = client.Issues.ListByRepo(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		for _, issue := range issues {
+			if issue.User == nil || issue.User.ID == nil {
+				continue
+			}
+
+			author, ok := userCache[*issue.User.ID]
+			if !ok {
+				var err error
+				author, _, err = client.Users.GetByID(ctx, *issue.User.ID)
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				userCache[*issue.User.ID] = author
+			}
+
+			var closedAt *time.Time
+			if issue.ClosedAt != nil {
+				t := issue.ClosedAt.Time
+				closedAt = &t
+			}
+
+			records = append(records, issueRecord{
+				Author:    author,
+				CreatedAt: issue.CreatedAt.Time,
+				ClosedAt:  closedAt,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return records, nil
+}
+
+// listStargazers returns who starred cfg.Owner/cfg.Repo and when. When sink
+// is non-nil, records are streamed to it as pages arrive instead of being
+// accumulated into the returned slices.
+func listStargazers(ctx context.Context, client *github.Client, cfg *Config, onlyID bool, sink Sink) ([]*github.User, []time.Time, error) {
 	opt := &github.ListOptions{PerPage: 100}
 	useTimeFilter := len(cfg.StartDate) > 0 && len(cfg.EndDate) > 0
 
@@ -268,8 +470,14 @@ func listStargazers(ctx context.Context, client *github.Client, cfg *Config, onl
 		times []time.Time
 	)
 	for {
-		stargazers, resp, err := client.Activity.ListStargazers(ctx, cfg.Owner, cfg.Repo, opt)
-		if err != nil {
+		var (
+			stargazers []*github.Stargazer
+			resp       *github.Response
+		)
+		if err := retryPage(func() (err error) {
+			stargazers, resp, err = client.Activity.ListStargazers(ctx, cfg.Owner, cfg.Repo, opt)
+			return err
+		}); err != nil {
 			return nil, nil, errors.Trace(err)
 		}
 
@@ -291,6 +499,13 @@ func listStargazers(ctx context.Context, client *github.Client, cfg *Config, onl
 				}
 			}
 
+			if sink != nil {
+				if err := sink.WriteUser(userRecord{User: user, Time: stargazer.StarredAt.Time}); err != nil {
+					return nil, nil, errors.Trace(err)
+				}
+				continue
+			}
+
 			users = append(users, user)
 			times = append(times, stargazer.StarredAt.Time)
 		}
@@ -0,0 +1,186 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpclient wraps an http.RoundTripper so GitHub's (and other
+// forges') primary/secondary rate limits don't take a long-running scrape
+// down mid-pagination: it proactively sleeps when a token is close to its
+// reset, retries abuse-detection responses with backoff, and picks the
+// least-exhausted token from a pool so one near its limit doesn't stall
+// the whole run.
+package httpclient
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+const maxRetries = 5
+
+// tokenState tracks how close one token in the pool is to its rate limit.
+// Its own mutex guards remaining/reset, since multiple in-flight
+// RoundTrips can pick and update the same token concurrently.
+type tokenState struct {
+	token string
+
+	mu        sync.Mutex
+	remaining int
+	reset     time.Time
+}
+
+func (s *tokenState) snapshot() (remaining int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remaining, s.reset
+}
+
+func (s *tokenState) update(resp *http.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v := resp.Header.Get("X-RateLimit-Remaining"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			s.remaining = n
+		}
+	}
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			s.reset = time.Unix(n, 0)
+		}
+	}
+}
+
+// Transport picks the least-exhausted token from a pool for each request,
+// proactively sleeping when that token is nearly exhausted, and retries
+// 403 "secondary rate limit"/abuse-detection responses with exponential
+// backoff plus jitter.
+type Transport struct {
+	Base http.RoundTripper
+
+	tokens []*tokenState
+}
+
+// NewTransport builds a Transport selecting across tokens. base is the
+// underlying RoundTripper (typically http.DefaultTransport); pass nil to
+// use it.
+func NewTransport(tokens []string, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	states := make([]*tokenState, len(tokens))
+	for i, t := range tokens {
+		// A token's quota is unknown until its first response; treat it as
+		// full so an unused token is preferred over one known to be nearly
+		// exhausted.
+		states[i] = &tokenState{token: t, remaining: math.MaxInt32}
+	}
+
+	return &Transport{Base: base, tokens: states}
+}
+
+// leastExhausted picks the token pool entry with the most remaining quota.
+// tokenState's own mutex (not Transport's) protects the fields read here,
+// so this is safe to call while other goroutines are updating them.
+func (t *Transport) leastExhausted() *tokenState {
+	if len(t.tokens) == 0 {
+		return nil
+	}
+
+	best := t.tokens[0]
+	bestRemaining, _ := best.snapshot()
+	for _, s := range t.tokens[1:] {
+		remaining, _ := s.snapshot()
+		if remaining > bestRemaining {
+			best, bestRemaining = s, remaining
+		}
+	}
+	return best
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	state := t.leastExhausted()
+	if state != nil {
+		remaining, reset := state.snapshot()
+		if remaining <= 1 && time.Now().Before(reset) {
+			time.Sleep(time.Until(reset))
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "token "+state.token)
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		resp, err = t.Base.RoundTrip(req)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		if state != nil {
+			state.update(resp)
+		}
+
+		if resp.StatusCode != http.StatusForbidden {
+			return resp, nil
+		}
+
+		wait, ok := retryAfter(resp)
+		if !ok {
+			return resp, nil
+		}
+		// Back off exponentially across repeated hits, even when the
+		// server's own hint is shorter.
+		if backoff := time.Second << uint(attempt); wait < backoff {
+			wait = backoff
+		}
+
+		log.Warnf("httpclient: secondary rate limit hit, retrying in %s (attempt %d/%d)", wait, attempt+1, maxRetries)
+		resp.Body.Close()
+		time.Sleep(wait)
+	}
+
+	return resp, nil
+}
+
+// retryAfter reports how long to back off for a 403 response and whether
+// it's worth retrying at all: a genuine permission error (bad/revoked
+// token) won't have Retry-After or an exhausted rate limit, and retrying
+// that would just spin.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			// Add jitter so a pool of tokens hitting the same secondary
+			// limit don't all retry in lockstep.
+			jitter := time.Duration(rand.Intn(500)) * time.Millisecond
+			return time.Duration(secs)*time.Second + jitter, true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			return time.Until(time.Unix(reset, 0)), true
+		}
+	}
+
+	return 0, false
+}
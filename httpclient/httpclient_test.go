@@ -0,0 +1,50 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterHonorsHeader(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+
+	wait, ok := retryAfter(resp)
+	if !ok {
+		t.Fatalf("expected retryAfter to say retry")
+	}
+	if wait < 2*time.Second {
+		t.Fatalf("expected at least 2s wait, got %s", wait)
+	}
+}
+
+func TestRetryAfterGivesUpOnPlainForbidden(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+
+	if _, ok := retryAfter(resp); ok {
+		t.Fatalf("expected retryAfter to give up on a 403 with no rate-limit headers")
+	}
+}
+
+func TestLeastExhaustedPicksMostRemaining(t *testing.T) {
+	tr := NewTransport([]string{"a", "b"}, nil)
+	tr.tokens[0].remaining = 1
+	tr.tokens[1].remaining = 100
+
+	if got := tr.leastExhausted(); got.token != "b" {
+		t.Fatalf("expected token b, got %s", got.token)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/oauth2"
+)
+
+func main() {
+	var (
+		owner      = flag.String("owner", "", "repository owner/namespace")
+		repo       = flag.String("repo", "", "repository name")
+		token      = flag.String("token", "", "access token")
+		tokens     = flag.String("tokens", "", "comma-separated token pool; each request uses the token with the most remaining quota")
+		provider   = flag.String("provider", "github", "github|gitlab|gitea|gogs")
+		baseURL    = flag.String("base-url", "", "self-hosted base URL (required for gitea/gogs)")
+		startDate  = flag.String("start", "", "range start date, used by --mode and time-filtered queries")
+		endDate    = flag.String("end", "", "range end date, used by --mode and time-filtered queries")
+		format     = flag.String("format", "tsv", "output format: tsv|csv|ndjson|parquet")
+		out        = flag.String("out", "", "output file path; stdout if empty")
+		resource   = flag.String("resource", "stargazers", "resource to stream through --format/--out: stargazers|forks|commits|watchers|issues")
+		mode       = flag.String("mode", "", modeSummary())
+		useGraphQL = flag.Bool("graphql", false, "use the GitHub v4 GraphQL API for stargazers/forks")
+		corpusDir  = flag.String("corpus-dir", "", "local corpus directory; enables offline incremental sync when set")
+		refresh    = flag.Bool("refresh", false, "force a full corpus resync (only with --corpus-dir)")
+	)
+	flag.Parse()
+
+	cfg := &Config{
+		Owner:     *owner,
+		Repo:      *repo,
+		Token:     *token,
+		StartDate: *startDate,
+		EndDate:   *endDate,
+		Provider:  *provider,
+		BaseURL:   *baseURL,
+	}
+	if *tokens != "" {
+		cfg.Tokens = strings.Split(*tokens, ",")
+	}
+
+	ctx := context.Background()
+
+	if err := run(ctx, cfg, *mode, *format, *out, *resource, *useGraphQL, *corpusDir, *refresh); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func run(ctx context.Context, cfg *Config, mode, format, out, resource string, useGraphQL bool, corpusDir string, refresh bool) error {
+	forge, err := newForge(ctx, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if mode != "" {
+		return errors.Trace(runMode(ctx, mode, forge, cfg))
+	}
+
+	w := os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	sink, err := newSink(format, w)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	switch {
+	case corpusDir != "":
+		corpus, err := NewCorpus(corpusDir)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		if err := corpus.Update(ctx, newGitHubClient(ctx, cfg), cfg, refresh); err != nil {
+			return errors.Trace(err)
+		}
+
+		start, end, err := rangeBounds(cfg)
+		if err != nil {
+			return errors.Trace(err)
+		}
+
+		users, times, err := corpus.Stargazers(cfg.Owner, cfg.Repo, start, end)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for i, user := range users {
+			if err := sink.WriteUser(userRecord{User: user, Time: times[i]}); err != nil {
+				return errors.Trace(err)
+			}
+		}
+
+	case useGraphQL && (resource == "" || resource == "stargazers" || resource == "forks"):
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.Token})
+		v4client := githubv4.NewClient(oauth2.NewClient(ctx, ts))
+
+		list := listStargazersGraphQL
+		if resource == "forks" {
+			list = listForkersGraphQL
+		}
+		users, times, err := list(ctx, v4client, cfg)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for i, user := range users {
+			if err := sink.WriteUser(userRecord{User: user, Time: times[i]}); err != nil {
+				return errors.Trace(err)
+			}
+		}
+
+	default:
+		if err := streamResource(ctx, forge, cfg, resource, sink); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	return errors.Trace(sink.Close())
+}
+
+// streamResource writes resource ("stargazers", "forks", "commits",
+// "watchers" or "issues") for cfg.Owner/cfg.Repo to sink. Stargazers,
+// forks and commits stream page-by-page since the Forge methods backing
+// them accept a Sink directly; watchers and issues have no such
+// pagination hook on Forge yet, so their (already fully fetched) results
+// are written to sink afterward instead.
+func streamResource(ctx context.Context, forge Forge, cfg *Config, resource string, sink Sink) error {
+	switch resource {
+	case "", "stargazers":
+		_, _, err := forge.ListStargazers(ctx, cfg, sink)
+		return errors.Trace(err)
+	case "forks":
+		_, _, err := forge.ListForks(ctx, cfg, sink)
+		return errors.Trace(err)
+	case "commits":
+		_, err := forge.ListCommits(ctx, cfg, sink)
+		return errors.Trace(err)
+	case "watchers":
+		users, _, err := forge.ListWatchers(ctx, cfg)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for _, user := range users {
+			if err := sink.WriteUser(userRecord{User: user}); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return nil
+	case "issues":
+		users, err := forge.ListIssues(ctx, cfg)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		for _, user := range users {
+			if err := sink.WriteUser(userRecord{User: user}); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown resource %q", resource)
+	}
+}
@@ -0,0 +1,422 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// Handler implements one --mode. It's handed the already-constructed Forge
+// and Config for the run so it doesn't need to know how the repo was
+// resolved (GitHub REST, GraphQL, GitLab, ...).
+type Handler func(ctx context.Context, forge Forge, cfg *Config) error
+
+// modeEntry pairs a Handler with a caveats string surfaced in modeSummary,
+// e.g. for modes that only work against a Forge backed by the GitHub REST
+// API today.
+type modeEntry struct {
+	Handler Handler
+	Caveats string
+}
+
+// needsCommitIssueRecords is the caveat shared by every mode built on
+// Forge.ListCommitRecords/ListIssueRecords, which only githubForge
+// implements; gitlabForge/giteaForge/gogsForge return NotImplemented.
+const needsCommitIssueRecords = "GitHub only: needs Forge.ListCommitRecords/ListIssueRecords, not yet implemented for gitlab/gitea/gogs"
+
+// modeRegistry mirrors the dispatcher style of gopherstats: every mode is
+// a named entry here rather than a big switch in main.
+var modeRegistry = map[string]modeEntry{
+	"range-stats":      {rangeStatsMode, needsCommitIssueRecords},
+	"issue-close":      {issueCloseMode, needsCommitIssueRecords},
+	"top-contributors": {topContributorsMode, needsCommitIssueRecords},
+	"new-vs-returning": {newVsReturningMode, needsCommitIssueRecords},
+	"email-discovery":  {emailDiscoveryMode, "GitHub only: needs Forge.ListCommits and ListStargazers; gitlab, gitea and gogs each return NotImplemented for at least one"},
+}
+
+// modeSummary renders the registry for -h, one line per mode (plus any
+// caveats) sorted by name so the help output is stable across runs.
+func modeSummary() string {
+	names := make([]string, 0, len(modeRegistry))
+	for name := range modeRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("available modes:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s\n", name)
+		if caveats := modeRegistry[name].Caveats; caveats != "" {
+			fmt.Fprintf(&b, "    (%s)\n", caveats)
+		}
+	}
+
+	return b.String()
+}
+
+// runMode looks up name in modeRegistry and runs it, or returns an error
+// listing the valid modes.
+func runMode(ctx context.Context, name string, forge Forge, cfg *Config) error {
+	entry, ok := modeRegistry[name]
+	if !ok {
+		return errors.Errorf("unknown mode %q\n%s", name, modeSummary())
+	}
+
+	return errors.Trace(entry.Handler(ctx, forge, cfg))
+}
+
+// weekBucket truncates t to the Monday that starts its week, used to
+// bucket commits/issues/stars/forks by week in range-stats.
+func weekBucket(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// weekCounts tallies each event type seen in one ISO week, keyed by
+// weekBucket in rangeStatsCounts.
+type weekCounts struct {
+	Commits, Issues, Stars, Forks int
+}
+
+// rangeStatsCounts is the pure computation behind rangeStatsMode: it
+// buckets commits/issues/stars/forks in [start, end) by week and tallies
+// commits+issues per author, factored out of rangeStatsMode so the week
+// bucketing and per-user tally can be unit tested without a Forge.
+func rangeStatsCounts(commits []commitRecord, issues []issueRecord, starTimes, forkTimes []time.Time, start, end time.Time) (weeks []time.Time, byWeek map[time.Time]weekCounts, byUser map[string]int) {
+	counts := make(map[time.Time]weekCounts)
+	bump := func(t time.Time, apply func(*weekCounts)) {
+		if !checkTime(start, end, t) {
+			return
+		}
+		w := weekBucket(t)
+		wc := counts[w]
+		apply(&wc)
+		counts[w] = wc
+	}
+
+	byUser = make(map[string]int)
+	for _, rec := range commits {
+		bump(rec.At, func(wc *weekCounts) { wc.Commits++ })
+		if rec.Author != nil && checkTime(start, end, rec.At) {
+			byUser[unifyStr(rec.Author.Login)]++
+		}
+	}
+	for _, rec := range issues {
+		bump(rec.CreatedAt, func(wc *weekCounts) { wc.Issues++ })
+		if rec.Author != nil && checkTime(start, end, rec.CreatedAt) {
+			byUser[unifyStr(rec.Author.Login)]++
+		}
+	}
+	for _, t := range starTimes {
+		bump(t, func(wc *weekCounts) { wc.Stars++ })
+	}
+	for _, t := range forkTimes {
+		bump(t, func(wc *weekCounts) { wc.Forks++ })
+	}
+
+	weeks = make([]time.Time, 0, len(counts))
+	for w := range counts {
+		weeks = append(weeks, w)
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].Before(weeks[j]) })
+
+	return weeks, counts, byUser
+}
+
+// rankedUsers sorts byUser's keys by count descending, login ascending to
+// break ties, so callers get a stable, deterministic print order.
+func rankedUsers(byUser map[string]int) []string {
+	users := make([]string, 0, len(byUser))
+	for login := range byUser {
+		users = append(users, login)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if byUser[users[i]] != byUser[users[j]] {
+			return byUser[users[i]] > byUser[users[j]]
+		}
+		return users[i] < users[j]
+	})
+	return users
+}
+
+// rangeStatsMode prints commit/issue/star/fork counts bucketed by week,
+// plus a per-user commit+issue count, for cfg.StartDate..cfg.EndDate.
+// Median issue time-to-close lives in issueCloseMode and the first-time
+// vs. repeat contributor split lives in newVsReturningMode — both are
+// registered alongside this mode rather than folded in here.
+func rangeStatsMode(ctx context.Context, forge Forge, cfg *Config) error {
+	start, end, err := rangeBounds(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	commits, err := forge.ListCommitRecords(ctx, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	issues, err := forge.ListIssueRecords(ctx, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	_, starTimes, err := forge.ListStargazers(ctx, cfg, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	_, forkTimes, err := forge.ListForks(ctx, cfg, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	weeks, byWeek, byUser := rangeStatsCounts(commits, issues, starTimes, forkTimes, start, end)
+
+	for _, w := range weeks {
+		wc := byWeek[w]
+		log.Infof("[range-stats] week of %s: %d commits, %d issues, %d stars, %d forks",
+			w.Format("2006-01-02"), wc.Commits, wc.Issues, wc.Stars, wc.Forks)
+	}
+
+	for _, login := range rankedUsers(byUser) {
+		log.Infof("[range-stats] user %s: %d commits+issues", login, byUser[login])
+	}
+
+	return nil
+}
+
+// medianTimeToClose returns the count of issues closed in [start, end) and
+// their median time-to-close, factored out of issueCloseMode so the
+// median math can be unit tested without a Forge.
+func medianTimeToClose(issues []issueRecord, start, end time.Time) (n int, median time.Duration) {
+	var durations []time.Duration
+	for _, rec := range issues {
+		if rec.ClosedAt == nil || !checkTime(start, end, *rec.ClosedAt) {
+			continue
+		}
+		durations = append(durations, rec.ClosedAt.Sub(rec.CreatedAt))
+	}
+	if len(durations) == 0 {
+		return 0, 0
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	median = durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		median = (durations[len(durations)/2-1] + durations[len(durations)/2]) / 2
+	}
+
+	return len(durations), median
+}
+
+// issueCloseMode reports the median time-to-close for issues closed
+// between cfg.StartDate and cfg.EndDate.
+func issueCloseMode(ctx context.Context, forge Forge, cfg *Config) error {
+	start, end, err := rangeBounds(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	issues, err := forge.ListIssueRecords(ctx, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	n, median := medianTimeToClose(issues, start, end)
+	if n == 0 {
+		log.Infof("[issue-close] no issues closed in range")
+		return nil
+	}
+
+	log.Infof("[issue-close] %d issues closed in range, median time-to-close %s", n, median)
+	return nil
+}
+
+// contributorCount is one author's commit count in rankCommitAuthors.
+type contributorCount struct {
+	Login string
+	Count int
+}
+
+// rankCommitAuthors tallies commits per author in [start, end) and sorts
+// by count descending, login ascending to break ties, factored out of
+// topContributorsMode so the ranking can be unit tested without a Forge.
+func rankCommitAuthors(commits []commitRecord, start, end time.Time) []contributorCount {
+	counts := make(map[string]int)
+	for _, rec := range commits {
+		if rec.Author == nil || !checkTime(start, end, rec.At) {
+			continue
+		}
+		counts[unifyStr(rec.Author.Login)]++
+	}
+
+	ranked := make([]contributorCount, 0, len(counts))
+	for login, count := range counts {
+		ranked = append(ranked, contributorCount{Login: login, Count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Login < ranked[j].Login
+	})
+
+	return ranked
+}
+
+// topContributorsMode ranks commit authors by number of commits in
+// cfg.StartDate..cfg.EndDate, most active first.
+func topContributorsMode(ctx context.Context, forge Forge, cfg *Config) error {
+	start, end, err := rangeBounds(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	commits, err := forge.ListCommitRecords(ctx, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	for _, c := range rankCommitAuthors(commits, start, end) {
+		log.Infof("[top-contributors] %s: %d commits", c.Login, c.Count)
+	}
+
+	return nil
+}
+
+// classifyNewVsReturning splits stargazers who starred in [start, end)
+// into first-time versus repeat contributors. A stargazer counts as
+// "returning" only if their earliest commit predates start; a contributor
+// whose first commit lands inside the window is "new", matching the
+// intent of a first-time-vs-repeat split rather than lifetime commit
+// authorship. Factored out of newVsReturningMode so the threshold can be
+// unit tested without a Forge.
+func classifyNewVsReturning(stargazers []*github.User, starTimes []time.Time, commits []commitRecord, start, end time.Time) (newCount, returningCount int) {
+	earliestCommit := make(map[string]time.Time)
+	for _, rec := range commits {
+		if rec.Author == nil {
+			continue
+		}
+		login := unifyStr(rec.Author.Login)
+		if first, ok := earliestCommit[login]; !ok || rec.At.Before(first) {
+			earliestCommit[login] = rec.At
+		}
+	}
+
+	for i, user := range stargazers {
+		if !checkTime(start, end, starTimes[i]) {
+			continue
+		}
+
+		if first, ok := earliestCommit[unifyStr(user.Login)]; ok && first.Before(start) {
+			returningCount++
+		} else {
+			newCount++
+		}
+	}
+
+	return newCount, returningCount
+}
+
+// newVsReturningMode splits stargazers in cfg.StartDate..cfg.EndDate into
+// first-time versus repeat contributors, using commit authorship as the
+// "has contributed" signal.
+func newVsReturningMode(ctx context.Context, forge Forge, cfg *Config) error {
+	start, end, err := rangeBounds(cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	stargazers, starTimes, err := forge.ListStargazers(ctx, cfg, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	commits, err := forge.ListCommitRecords(ctx, cfg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	newCount, returningCount := classifyNewVsReturning(stargazers, starTimes, commits, start, end)
+
+	log.Infof("[new-vs-returning] new=%d returning=%d", newCount, returningCount)
+	return nil
+}
+
+// emailDiscoveryMode cross-references commit author emails against
+// stargazer/forker logins to build a username -> email map useful for
+// outreach.
+func emailDiscoveryMode(ctx context.Context, forge Forge, cfg *Config) error {
+	commitAuthors, err := forge.ListCommits(ctx, cfg, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	emailByLogin := make(map[string]string)
+	for _, user := range commitAuthors {
+		login := unifyStr(user.Login)
+		email := unifyStr(user.Email)
+		if login == "" || email == "" {
+			continue
+		}
+		emailByLogin[login] = email
+	}
+
+	stargazers, _, err := forge.ListStargazers(ctx, cfg, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	forkers, _, err := forge.ListForks(ctx, cfg, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	found := 0
+	for _, user := range append(stargazers, forkers...) {
+		login := unifyStr(user.Login)
+		if email, ok := emailByLogin[login]; ok {
+			log.Infof("[email-discovery] %s\t%s", login, email)
+			found++
+		}
+	}
+
+	log.Infof("[email-discovery] matched %d of %d stargazers/forkers to a commit email", found, len(stargazers)+len(forkers))
+	return nil
+}
+
+// rangeBounds parses cfg.StartDate/cfg.EndDate, which every range-based
+// mode needs.
+func rangeBounds(cfg *Config) (time.Time, time.Time, error) {
+	start, err := parseDate(cfg.StartDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Trace(err)
+	}
+
+	end, err := parseDate(cfg.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Trace(err)
+	}
+
+	return start, end, nil
+}
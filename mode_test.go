@@ -0,0 +1,260 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/juju/errors"
+)
+
+func TestWeekBucketTruncatesToMonday(t *testing.T) {
+	// 2024-01-10 was a Wednesday; its week starts Monday 2024-01-08.
+	wed := time.Date(2024, 1, 10, 15, 30, 0, 0, time.UTC)
+	want := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	if got := weekBucket(wed); !got.Equal(want) {
+		t.Fatalf("weekBucket(%v) = %v, want %v", wed, got, want)
+	}
+}
+
+func TestWeekBucketIsIdempotentOnMonday(t *testing.T) {
+	mon := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	if got := weekBucket(mon); !got.Equal(mon) {
+		t.Fatalf("weekBucket(%v) = %v, want %v", mon, got, mon)
+	}
+}
+
+func testUser(login string) *github.User {
+	return &github.User{Login: &login}
+}
+
+func TestRangeStatsCountsBucketsByWeekAndUser(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	commits := []commitRecord{
+		{Author: testUser("alice"), At: time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)},
+		{Author: testUser("alice"), At: time.Date(2024, 1, 9, 10, 0, 0, 0, time.UTC)},
+		{Author: testUser("bob"), At: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)},
+		// Outside the range entirely; must not be counted.
+		{Author: testUser("carol"), At: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	issues := []issueRecord{
+		{Author: testUser("bob"), CreatedAt: time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)},
+	}
+	starTimes := []time.Time{time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)}
+	forkTimes := []time.Time{time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)}
+
+	weeks, byWeek, byUser := rangeStatsCounts(commits, issues, starTimes, forkTimes, start, end)
+
+	if len(weeks) != 2 {
+		t.Fatalf("expected 2 distinct weeks, got %d: %v", len(weeks), weeks)
+	}
+
+	week1 := weekBucket(time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC))
+	week2 := weekBucket(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	if wc := byWeek[week1]; wc.Commits != 2 || wc.Issues != 1 || wc.Stars != 1 || wc.Forks != 0 {
+		t.Fatalf("week1 counts = %+v, want commits=2 issues=1 stars=1 forks=0", wc)
+	}
+	if wc := byWeek[week2]; wc.Commits != 1 || wc.Issues != 0 || wc.Stars != 0 || wc.Forks != 1 {
+		t.Fatalf("week2 counts = %+v, want commits=1 issues=0 stars=0 forks=1", wc)
+	}
+
+	if byUser["alice"] != 2 || byUser["bob"] != 2 {
+		t.Fatalf("byUser = %+v, want alice=2 bob=2", byUser)
+	}
+	if _, ok := byUser["carol"]; ok {
+		t.Fatalf("byUser = %+v, carol's out-of-range commit should not be counted", byUser)
+	}
+}
+
+func TestMedianTimeToClose(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	closedAt := func(d time.Time) *time.Time { return &d }
+	issues := []issueRecord{
+		{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ClosedAt: closedAt(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))}, // 24h
+		{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ClosedAt: closedAt(time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC))}, // 72h
+		{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ClosedAt: closedAt(time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC))}, // 120h
+		{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},                                                                  // never closed; must not count
+	}
+
+	n, median := medianTimeToClose(issues, start, end)
+	if n != 3 {
+		t.Fatalf("n = %d, want 3", n)
+	}
+	if median != 72*time.Hour {
+		t.Fatalf("median = %s, want 72h", median)
+	}
+}
+
+func TestMedianTimeToCloseNoneClosed(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	n, median := medianTimeToClose(nil, start, end)
+	if n != 0 || median != 0 {
+		t.Fatalf("n=%d median=%s, want 0/0", n, median)
+	}
+}
+
+func TestRankCommitAuthorsOrdersByCountThenLogin(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	commits := []commitRecord{
+		{Author: testUser("bob"), At: at},
+		{Author: testUser("alice"), At: at},
+		{Author: testUser("alice"), At: at},
+		{Author: testUser("carol"), At: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}, // outside range
+	}
+
+	ranked := rankCommitAuthors(commits, start, end)
+	if len(ranked) != 2 {
+		t.Fatalf("expected 2 ranked authors, got %d: %+v", len(ranked), ranked)
+	}
+	if ranked[0].Login != "alice" || ranked[0].Count != 2 {
+		t.Fatalf("ranked[0] = %+v, want alice/2", ranked[0])
+	}
+	if ranked[1].Login != "bob" || ranked[1].Count != 1 {
+		t.Fatalf("ranked[1] = %+v, want bob/1", ranked[1])
+	}
+}
+
+func TestClassifyNewVsReturning(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	stargazers := []*github.User{testUser("alice"), testUser("bob"), testUser("carol")}
+	starTimes := []time.Time{
+		time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC),
+	}
+
+	commits := []commitRecord{
+		// alice contributed well before the window: returning.
+		{Author: testUser("alice"), At: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)},
+		// bob's first commit lands inside the window: new, not returning.
+		{Author: testUser("bob"), At: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		// carol has never committed: new.
+	}
+
+	newCount, returningCount := classifyNewVsReturning(stargazers, starTimes, commits, start, end)
+	if newCount != 2 {
+		t.Fatalf("newCount = %d, want 2", newCount)
+	}
+	if returningCount != 1 {
+		t.Fatalf("returningCount = %d, want 1", returningCount)
+	}
+}
+
+// fakeForge is a minimal, in-memory Forge used to exercise the --mode
+// handlers end-to-end without hitting any network API.
+type fakeForge struct {
+	stargazers []*github.User
+	starTimes  []time.Time
+	forkers    []*github.User
+	forkTimes  []time.Time
+	commits    []commitRecord
+	issues     []issueRecord
+}
+
+func (f *fakeForge) ListStargazers(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	return f.stargazers, f.starTimes, nil
+}
+
+func (f *fakeForge) ListForks(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, []time.Time, error) {
+	return f.forkers, f.forkTimes, nil
+}
+
+func (f *fakeForge) ListWatchers(ctx context.Context, cfg *Config) ([]*github.User, []time.Time, error) {
+	return nil, nil, errors.NotImplementedf("fakeForge.ListWatchers")
+}
+
+func (f *fakeForge) ListIssues(ctx context.Context, cfg *Config) ([]*github.User, error) {
+	return nil, errors.NotImplementedf("fakeForge.ListIssues")
+}
+
+func (f *fakeForge) ListCommits(ctx context.Context, cfg *Config, sink Sink) ([]*github.User, error) {
+	return nil, errors.NotImplementedf("fakeForge.ListCommits")
+}
+
+func (f *fakeForge) ListCommitRecords(ctx context.Context, cfg *Config) ([]commitRecord, error) {
+	return f.commits, nil
+}
+
+func (f *fakeForge) ListIssueRecords(ctx context.Context, cfg *Config) ([]issueRecord, error) {
+	return f.issues, nil
+}
+
+func (f *fakeForge) GetUserByID(ctx context.Context, id int) (*github.User, error) {
+	return nil, errors.NotImplementedf("fakeForge.GetUserByID")
+}
+
+func testRangeConfig() *Config {
+	return &Config{Owner: "o", Repo: "r", StartDate: "2024-01-01", EndDate: "2024-01-31"}
+}
+
+func TestRangeStatsModeRunsAgainstFakeForge(t *testing.T) {
+	forge := &fakeForge{
+		commits: []commitRecord{{Author: testUser("alice"), At: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)}},
+		issues:  []issueRecord{{Author: testUser("bob"), CreatedAt: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	if err := rangeStatsMode(context.Background(), forge, testRangeConfig()); err != nil {
+		t.Fatalf("rangeStatsMode: %v", err)
+	}
+}
+
+func TestIssueCloseModeRunsAgainstFakeForge(t *testing.T) {
+	closedAt := time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)
+	forge := &fakeForge{
+		issues: []issueRecord{{CreatedAt: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), ClosedAt: &closedAt}},
+	}
+
+	if err := issueCloseMode(context.Background(), forge, testRangeConfig()); err != nil {
+		t.Fatalf("issueCloseMode: %v", err)
+	}
+}
+
+func TestTopContributorsModeRunsAgainstFakeForge(t *testing.T) {
+	forge := &fakeForge{
+		commits: []commitRecord{{Author: testUser("alice"), At: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	if err := topContributorsMode(context.Background(), forge, testRangeConfig()); err != nil {
+		t.Fatalf("topContributorsMode: %v", err)
+	}
+}
+
+func TestNewVsReturningModeRunsAgainstFakeForge(t *testing.T) {
+	forge := &fakeForge{
+		stargazers: []*github.User{testUser("alice")},
+		starTimes:  []time.Time{time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)},
+		commits:    []commitRecord{{Author: testUser("alice"), At: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	if err := newVsReturningMode(context.Background(), forge, testRangeConfig()); err != nil {
+		t.Fatalf("newVsReturningMode: %v", err)
+	}
+}
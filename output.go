@@ -0,0 +1,173 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/juju/errors"
+)
+
+// userColumns is the column schema shared by every Sink implementation, so
+// a Parquet reader downstream (DuckDB, Spark, BigQuery, ...) sees the same
+// typed layout regardless of which format produced the file.
+var userColumns = []string{
+	"id", "login", "name", "email", "location", "company",
+	"blog", "bio", "public_repos", "following", "followers",
+	"html_url", "timestamp",
+}
+
+// userRecord is one row written to a Sink: a user plus the optional event
+// time (star/fork date) associated with them.
+type userRecord struct {
+	User *github.User
+	Time time.Time
+}
+
+// Sink streams user records to an output format. Callers write records as
+// pages arrive instead of buffering the whole result set, so large repos
+// don't have to hold every stargazer/forker in memory at once.
+type Sink interface {
+	WriteUser(rec userRecord) error
+	Close() error
+}
+
+// newSink builds the Sink named by format, writing to w. Supported formats
+// are "tsv" (default, matches the historical log.Infof output), "csv",
+// "ndjson" and "parquet".
+func newSink(format string, w io.Writer) (Sink, error) {
+	switch format {
+	case "", "tsv":
+		return &tsvSink{w: w}, nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(userColumns); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return &csvSink{w: cw}, nil
+	case "ndjson":
+		return &ndjsonSink{enc: json.NewEncoder(w)}, nil
+	case "parquet":
+		return newParquetSink(w)
+	default:
+		return nil, errors.Errorf("unknown output format %q", format)
+	}
+}
+
+func userRow(rec userRecord) []string {
+	return []string{
+		unifyInt(rec.User.ID),
+		unifyStr(rec.User.Login),
+		unifyStr(rec.User.Name),
+		unifyStr(rec.User.Email),
+		unifyStr(rec.User.Location),
+		unifyStr(rec.User.Company),
+		unifyStr(rec.User.Blog),
+		unifyStr(rec.User.Bio),
+		unifyInt(rec.User.PublicRepos),
+		unifyInt(rec.User.Following),
+		unifyInt(rec.User.Followers),
+		unifyStr(rec.User.HTMLURL),
+		unifyDate(rec.Time),
+	}
+}
+
+// tsvSink preserves the tab-delimited layout printUsers used to write via
+// log.Infof, minus the logging.
+type tsvSink struct {
+	w io.Writer
+}
+
+func (s *tsvSink) WriteUser(rec userRecord) error {
+	row := userRow(rec)
+	for i, field := range row {
+		if i > 0 {
+			if _, err := io.WriteString(s.w, "\t"); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if _, err := io.WriteString(s.w, field); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	_, err := io.WriteString(s.w, "\n")
+	return errors.Trace(err)
+}
+
+func (s *tsvSink) Close() error { return nil }
+
+type csvSink struct {
+	w *csv.Writer
+}
+
+func (s *csvSink) WriteUser(rec userRecord) error {
+	return errors.Trace(s.w.Write(userRow(rec)))
+}
+
+func (s *csvSink) Close() error {
+	s.w.Flush()
+	return errors.Trace(s.w.Error())
+}
+
+// ndjsonRecord mirrors userColumns field-for-field (including the
+// star/fork timestamp) so switching --format doesn't change the column
+// set, only the encoding.
+type ndjsonRecord struct {
+	ID          string `json:"id"`
+	Login       string `json:"login"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Location    string `json:"location"`
+	Company     string `json:"company"`
+	Blog        string `json:"blog"`
+	Bio         string `json:"bio"`
+	PublicRepos string `json:"public_repos"`
+	Following   string `json:"following"`
+	Followers   string `json:"followers"`
+	HTMLURL     string `json:"html_url"`
+	Timestamp   string `json:"timestamp"`
+}
+
+func newNDJSONRecord(rec userRecord) ndjsonRecord {
+	row := userRow(rec)
+	return ndjsonRecord{
+		ID:          row[0],
+		Login:       row[1],
+		Name:        row[2],
+		Email:       row[3],
+		Location:    row[4],
+		Company:     row[5],
+		Blog:        row[6],
+		Bio:         row[7],
+		PublicRepos: row[8],
+		Following:   row[9],
+		Followers:   row[10],
+		HTMLURL:     row[11],
+		Timestamp:   row[12],
+	}
+}
+
+type ndjsonSink struct {
+	enc *json.Encoder
+}
+
+func (s *ndjsonSink) WriteUser(rec userRecord) error {
+	return errors.Trace(s.enc.Encode(newNDJSONRecord(rec)))
+}
+
+func (s *ndjsonSink) Close() error { return nil }
@@ -0,0 +1,123 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func testUserRecord() userRecord {
+	id := 7
+	login := "octocat"
+	return userRecord{User: &github.User{ID: &id, Login: &login}}
+}
+
+func TestTSVSinkWriteUser(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := newSink("tsv", &buf)
+	if err != nil {
+		t.Fatalf("newSink: %v", err)
+	}
+
+	if err := sink.WriteUser(testUserRecord()); err != nil {
+		t.Fatalf("WriteUser: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "octocat") {
+		t.Fatalf("expected output to contain login, got %q", buf.String())
+	}
+}
+
+func TestCSVSinkWritesHeaderOnce(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := newSink("csv", &buf)
+	if err != nil {
+		t.Fatalf("newSink: %v", err)
+	}
+
+	if err := sink.WriteUser(testUserRecord()); err != nil {
+		t.Fatalf("WriteUser: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header + 1 row, got %d lines: %q", len(lines), buf.String())
+	}
+}
+
+func TestNDJSONSinkWritesOneObjectPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := newSink("ndjson", &buf)
+	if err != nil {
+		t.Fatalf("newSink: %v", err)
+	}
+
+	if err := sink.WriteUser(testUserRecord()); err != nil {
+		t.Fatalf("WriteUser: %v", err)
+	}
+	if err := sink.WriteUser(testUserRecord()); err != nil {
+		t.Fatalf("WriteUser: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %d: %q", len(lines), buf.String())
+	}
+}
+
+func TestNDJSONSinkMatchesUserColumnsSchema(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := newSink("ndjson", &buf)
+	if err != nil {
+		t.Fatalf("newSink: %v", err)
+	}
+
+	rec := testUserRecord()
+	rec.Time = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := sink.WriteUser(rec); err != nil {
+		t.Fatalf("WriteUser: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("decoding NDJSON line: %v", err)
+	}
+
+	for _, col := range userColumns {
+		if _, ok := decoded[col]; !ok {
+			t.Fatalf("NDJSON record missing column %q: %v", col, decoded)
+		}
+	}
+	if decoded["timestamp"] == "" {
+		t.Fatalf("expected non-empty timestamp, got %v", decoded)
+	}
+}
+
+func TestNewSinkUnknownFormat(t *testing.T) {
+	if _, err := newSink("xml", &bytes.Buffer{}); err == nil {
+		t.Fatalf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+
+	"github.com/juju/errors"
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetUser mirrors userColumns and gets its Parquet schema generated
+// from the struct tags below.
+type parquetUser struct {
+	ID          int64  `parquet:"name=id, type=INT64"`
+	Login       string `parquet:"name=login, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Name        string `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Email       string `parquet:"name=email, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Location    string `parquet:"name=location, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Company     string `parquet:"name=company, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Blog        string `parquet:"name=blog, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Bio         string `parquet:"name=bio, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PublicRepos int64  `parquet:"name=public_repos, type=INT64"`
+	Following   int64  `parquet:"name=following, type=INT64"`
+	Followers   int64  `parquet:"name=followers, type=INT64"`
+	HTMLURL     string `parquet:"name=html_url, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Timestamp   string `parquet:"name=timestamp, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// parquetSink batches records into a columnar Parquet file, typed per
+// parquetUser, for downstream analytics (DuckDB, Spark, BigQuery).
+type parquetSink struct {
+	fw *writerfile.WriterFile
+	pw *writer.ParquetWriter
+}
+
+func newParquetSink(w io.Writer) (Sink, error) {
+	fw := writerfile.NewWriterFile(w)
+
+	pw, err := writer.NewParquetWriter(fw, new(parquetUser), 4)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	return &parquetSink{fw: fw, pw: pw}, nil
+}
+
+func (s *parquetSink) WriteUser(rec userRecord) error {
+	row := userRow(rec)
+
+	return errors.Trace(s.pw.Write(parquetUser{
+		ID:          int64(rec.User.GetID()),
+		Login:       row[1],
+		Name:        row[2],
+		Email:       row[3],
+		Location:    row[4],
+		Company:     row[5],
+		Blog:        row[6],
+		Bio:         row[7],
+		PublicRepos: int64(rec.User.GetPublicRepos()),
+		Following:   int64(rec.User.GetFollowing()),
+		Followers:   int64(rec.User.GetFollowers()),
+		HTMLURL:     row[11],
+		Timestamp:   row[12],
+	}))
+}
+
+func (s *parquetSink) Close() error {
+	if err := s.pw.WriteStop(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(s.fw.Close())
+}